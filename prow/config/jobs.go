@@ -0,0 +1,71 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config knows how to read and parse config.yaml.
+package config
+
+import "k8s.io/test-infra/prow/kube"
+
+// Presubmit is the job-specific trigger info.
+type Presubmit struct {
+	Name  string        `json:"name"`
+	Agent string        `json:"agent"`
+	Spec  *kube.PodSpec `json:"spec,omitempty"`
+
+	RerunCommand string `json:"rerun_command,omitempty"`
+	Context      string `json:"context,omitempty"`
+	SkipReport   bool   `json:"skip_report,omitempty"`
+
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	RunAfterSuccess []Presubmit `json:"run_after_success,omitempty"`
+}
+
+// Postsubmit runs on push events.
+type Postsubmit struct {
+	Name  string        `json:"name"`
+	Agent string        `json:"agent"`
+	Spec  *kube.PodSpec `json:"spec,omitempty"`
+
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	RunAfterSuccess []Postsubmit `json:"run_after_success,omitempty"`
+}
+
+// Periodic runs on a timer.
+type Periodic struct {
+	Name  string        `json:"name"`
+	Agent string        `json:"agent"`
+	Spec  *kube.PodSpec `json:"spec,omitempty"`
+
+	Interval string `json:"interval"`
+
+	RunAfterSuccess []Periodic `json:"run_after_success,omitempty"`
+}
+
+// MultiReplica runs as a set of coordinating replica pods, such as a JAX,
+// MPI, or PyTorch distributed test harness, instead of a single pod.
+type MultiReplica struct {
+	Name  string        `json:"name"`
+	Agent string        `json:"agent"`
+	Spec  *kube.PodSpec `json:"spec,omitempty"`
+
+	// Replicas is the number of pods to run. Values less than 2 behave like
+	// an ordinary single-pod job.
+	Replicas int `json:"replicas"`
+
+	RunAfterSuccess []MultiReplica `json:"run_after_success,omitempty"`
+}
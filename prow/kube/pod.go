@@ -0,0 +1,96 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+// Pod is a stripped down version of the Kubernetes v1.Pod type that prow
+// serializes when scheduling a ProwJob's test workload.
+type Pod struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
+	Metadata ObjectMeta `json:"metadata,omitempty"`
+	Spec     PodSpec    `json:"spec,omitempty"`
+	Status   PodStatus  `json:"status,omitempty"`
+}
+
+// PodSpec is a stripped down version of v1.PodSpec.
+type PodSpec struct {
+	RestartPolicy string      `json:"restartPolicy,omitempty"`
+	Containers    []Container `json:"containers"`
+
+	// SchedulerName requests that the pod be scheduled by a particular
+	// scheduler instead of the Kubernetes default scheduler. Batch
+	// schedulers such as kube-batch or volcano use this hook to perform
+	// gang scheduling.
+	SchedulerName string `json:"schedulerName,omitempty"`
+
+	NodeSelector      map[string]string `json:"nodeSelector,omitempty"`
+	Tolerations       []Toleration      `json:"tolerations,omitempty"`
+	PriorityClassName string            `json:"priorityClassName,omitempty"`
+}
+
+// Container is a stripped down version of v1.Container.
+type Container struct {
+	Name      string               `json:"name"`
+	Image     string               `json:"image,omitempty"`
+	Command   []string             `json:"command,omitempty"`
+	Args      []string             `json:"args,omitempty"`
+	Env       []EnvVar             `json:"env,omitempty"`
+	Resources ResourceRequirements `json:"resources,omitempty"`
+}
+
+// EnvVar is a stripped down version of v1.EnvVar.
+type EnvVar struct {
+	Name  string `json:"name"`
+	Value string `json:"value,omitempty"`
+}
+
+// ResourceRequirements is a stripped down version of v1.ResourceRequirements.
+type ResourceRequirements struct {
+	Limits   map[string]string `json:"limits,omitempty"`
+	Requests map[string]string `json:"requests,omitempty"`
+}
+
+// Toleration is a stripped down version of v1.Toleration.
+type Toleration struct {
+	Key      string `json:"key,omitempty"`
+	Operator string `json:"operator,omitempty"`
+	Value    string `json:"value,omitempty"`
+	Effect   string `json:"effect,omitempty"`
+}
+
+// PodStatus is a stripped down version of v1.PodStatus.
+type PodStatus struct {
+	Phase string `json:"phase,omitempty"`
+}
+
+// Service is a stripped down version of v1.Service.
+type Service struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
+	Metadata ObjectMeta  `json:"metadata,omitempty"`
+	Spec     ServiceSpec `json:"spec,omitempty"`
+}
+
+// ServiceSpec is a stripped down version of v1.ServiceSpec.
+type ServiceSpec struct {
+	// ClusterIP set to "None" makes the Service headless, so its DNS
+	// records resolve directly to its selected pods' addresses.
+	ClusterIP string            `json:"clusterIP,omitempty"`
+	Selector  map[string]string `json:"selector,omitempty"`
+}
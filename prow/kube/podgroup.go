@@ -0,0 +1,90 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kube
+
+// GangScheduleGroupNameAnnotation is set on pods that belong to a PodGroup so
+// that a batch scheduler (kube-batch, volcano) can identify which pods must
+// be started together.
+const GangScheduleGroupNameAnnotation = "scheduling.k8s.io/group-name"
+
+// DefaultGangSchedulerName is used as the pod's spec.schedulerName when a
+// ProwJob requests gang scheduling but does not override it.
+const DefaultGangSchedulerName = "kube-batch"
+
+// GangSchedule configures co-scheduling of a ProwJob's pod(s) through a
+// batch scheduler that understands the PodGroup CRD, such as kube-batch or
+// volcano. When set, ProwJobToPod emits a PodGroup alongside the Pod and the
+// batch scheduler withholds starting any of the group's containers until
+// enough resources are available to start them all at once.
+type GangSchedule struct {
+	// MinMember is the minimum number of pods that must be scheduled
+	// together before the batch scheduler starts any of them.
+	MinMember int `json:"min_member"`
+	// MinResources is the minimum amount of resources that must be
+	// available across the cluster before the group is admitted.
+	MinResources map[string]string `json:"min_resources,omitempty"`
+	// Queue is the scheduling queue the PodGroup is submitted to.
+	Queue string `json:"queue,omitempty"`
+	// PriorityClassName is set as the priorityClassName of the ProwJob's
+	// pod(s). It is not carried on the PodGroup.
+	PriorityClassName string `json:"priority_class_name,omitempty"`
+	// SchedulerName overrides the default scheduler used to admit the
+	// gang. Defaults to DefaultGangSchedulerName when empty.
+	SchedulerName string `json:"scheduler_name,omitempty"`
+}
+
+// PodGroupPhase is the lifecycle phase of a PodGroup.
+type PodGroupPhase string
+
+// Various PodGroup phases.
+const (
+	// PodGroupInqueue means the group is waiting for enough resources to
+	// become available.
+	PodGroupInqueue PodGroupPhase = "Inqueue"
+	// PodGroupRunning means the batch scheduler has admitted the group and
+	// started its pods.
+	PodGroupRunning PodGroupPhase = "Running"
+)
+
+// PodGroupAPIVersion is the apiVersion of the PodGroup CRD understood by
+// kube-batch/volcano style schedulers.
+const PodGroupAPIVersion = "scheduling.incubator.k8s.io/v1alpha1"
+
+// PodGroup is a stripped down version of the kube-batch/volcano PodGroup CRD.
+// A batch scheduler watches PodGroups and will not start any of a group's
+// member pods until MinMember of them can be admitted at once, which
+// prevents partial-start deadlocks for co-scheduled multi-pod jobs.
+type PodGroup struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
+	Metadata ObjectMeta     `json:"metadata,omitempty"`
+	Spec     PodGroupSpec   `json:"spec,omitempty"`
+	Status   PodGroupStatus `json:"status,omitempty"`
+}
+
+// PodGroupSpec configures how many pods must be admitted together.
+type PodGroupSpec struct {
+	MinMember    int               `json:"minMember,omitempty"`
+	MinResources map[string]string `json:"minResources,omitempty"`
+	Queue        string            `json:"queue,omitempty"`
+}
+
+// PodGroupStatus reports where the PodGroup is in its lifecycle.
+type PodGroupStatus struct {
+	Phase PodGroupPhase `json:"phase,omitempty"`
+}
@@ -0,0 +1,182 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kube holds the schema for ProwJobs and the other Kubernetes
+// objects that Prow creates in order to run them.
+package kube
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ProwJobType specifies how the job is triggered.
+type ProwJobType string
+
+// Various types of jobs.
+const (
+	// PresubmitJob means it runs on unmerged PRs.
+	PresubmitJob ProwJobType = "presubmit"
+	// PostsubmitJob means it runs on each new commit.
+	PostsubmitJob ProwJobType = "postsubmit"
+	// Periodic job means it runs on a time-basis, unrelated to git changes.
+	PeriodicJob ProwJobType = "periodic"
+	// BatchJob means it tests multiple unmerged PRs at the same time.
+	BatchJob ProwJobType = "batch"
+	// MultiReplicaJob means it runs as a set of coordinating replicas, such
+	// as a JAX, MPI, or PyTorch distributed test harness.
+	MultiReplicaJob ProwJobType = "multi-replica"
+)
+
+// ProwJobState specifies whether the job is running
+type ProwJobState string
+
+// Various job states.
+const (
+	// TriggeredState means the job has been created but not yet scheduled.
+	TriggeredState ProwJobState = "triggered"
+	// EnqueuedState means the job has been created and is waiting on an
+	// admission controller to allow it to proceed to PendingState.
+	EnqueuedState ProwJobState = "enqueued"
+	// PendingState means the job is scheduled but not yet running.
+	PendingState ProwJobState = "pending"
+	// SuccessState means the job completed without error (exit 0)
+	SuccessState ProwJobState = "success"
+	// FailureState means the job completed with errors (exit non-zero)
+	FailureState ProwJobState = "failure"
+	// AbortedState means prow killed the job early (new commit pushed, perhaps).
+	AbortedState ProwJobState = "aborted"
+	// ErrorState means the job could not schedule (bad configuration, perhaps)
+	ErrorState ProwJobState = "error"
+)
+
+// ProwJobAgent specifies the controller (such as plank or jenkins-operator) that runs the job.
+type ProwJobAgent string
+
+const (
+	// KubernetesAgent means prow will schedule the job with a kubernetes pod spec.
+	KubernetesAgent ProwJobAgent = "kubernetes"
+	// JenkinsAgent means prow will schedule the job via jenkins.
+	JenkinsAgent ProwJobAgent = "jenkins"
+)
+
+// Labels that prow attaches to the ProwJobs and Pods it creates.
+const (
+	// CreatedByProw is added on pods created by prow.
+	CreatedByProw = "created-by-prow"
+	// ProwJobTypeLabel is added in pods to indicate the type of job its running.
+	ProwJobTypeLabel = "prow.k8s.io/job-type"
+	// ProwJobIDLabel is added in pods to link them to the job that created them.
+	ProwJobIDLabel = "prow.k8s.io/id"
+	// ProwJobNameLabel carries the ProwJob's name, shared by every pod of a
+	// multi-replica job, so a headless Service can select all of them.
+	ProwJobNameLabel = "prow.k8s.io/prowjob-name"
+	// ProwJobReplicaIndexLabel is added to each pod of a multi-replica
+	// ProwJob with its replica index.
+	ProwJobReplicaIndexLabel = "prow.k8s.io/replica-index"
+)
+
+// Annotations that prow attaches to the Pods it creates.
+const (
+	// ProwJobAnnotation is the annotation that holds the job name.
+	ProwJobAnnotation = "prow.k8s.io/job"
+)
+
+// ProwJob contains the spec as well as runtime metadata.
+type ProwJob struct {
+	APIVersion string `json:"apiVersion,omitempty"`
+	Kind       string `json:"kind,omitempty"`
+
+	Metadata ObjectMeta    `json:"metadata,omitempty"`
+	Spec     ProwJobSpec   `json:"spec,omitempty"`
+	Status   ProwJobStatus `json:"status,omitempty"`
+}
+
+// ObjectMeta is a subset of the Kubernetes object metadata that prow cares
+// about when it serializes its own objects.
+type ObjectMeta struct {
+	Name        string            `json:"name,omitempty"`
+	Namespace   string            `json:"namespace,omitempty"`
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// ProwJobSpec configures the details of the test run.
+type ProwJobSpec struct {
+	Type  ProwJobType  `json:"type,omitempty"`
+	Agent ProwJobAgent `json:"agent,omitempty"`
+	Job   string       `json:"job,omitempty"`
+	Refs  Refs         `json:"refs,omitempty"`
+
+	Report       bool   `json:"report,omitempty"`
+	Context      string `json:"context,omitempty"`
+	RerunCommand string `json:"rerun_command,omitempty"`
+
+	// MaxConcurrency restricts the total number of instances of this job
+	// that can run at once.
+	MaxConcurrency int `json:"max_concurrency,omitempty"`
+
+	PodSpec PodSpec `json:"pod_spec,omitempty"`
+
+	// GangSchedule requests that this ProwJob's pod(s) be co-scheduled by a
+	// batch scheduler through a PodGroup, rather than started independently
+	// by the default Kubernetes scheduler.
+	GangSchedule *GangSchedule `json:"gang_schedule,omitempty"`
+
+	// Replicas is the number of coordinating pods a MultiReplicaJob runs.
+	// Values less than 2 are treated as a single, ordinary pod.
+	Replicas int `json:"replicas,omitempty"`
+
+	RunAfterSuccess []ProwJobSpec `json:"run_after_success,omitempty"`
+}
+
+// ProwJobStatus provides runtime information on the job.
+type ProwJobStatus struct {
+	StartTime      time.Time    `json:"startTime,omitempty"`
+	CompletionTime time.Time    `json:"completionTime,omitempty"`
+	State          ProwJobState `json:"state,omitempty"`
+	Description    string       `json:"description,omitempty"`
+	URL            string       `json:"url,omitempty"`
+	PodName        string       `json:"pod_name,omitempty"`
+}
+
+// Refs describes how the repo was constructed.
+type Refs struct {
+	Org  string `json:"org"`
+	Repo string `json:"repo"`
+
+	BaseRef string `json:"base_ref,omitempty"`
+	BaseSHA string `json:"base_sha,omitempty"`
+
+	Pulls []Pull `json:"pulls,omitempty"`
+}
+
+// Pull describes a pull request at a particular point in time.
+type Pull struct {
+	Number int    `json:"number"`
+	Author string `json:"author"`
+	SHA    string `json:"sha"`
+}
+
+// String constructs the string used in the PULL_REFS environment variable.
+func (r Refs) String() string {
+	rs := []string{fmt.Sprintf("%s:%s", r.BaseRef, r.BaseSHA)}
+	for _, pull := range r.Pulls {
+		rs = append(rs, fmt.Sprintf("%d:%s", pull.Number, pull.SHA))
+	}
+	return strings.Join(rs, ",")
+}
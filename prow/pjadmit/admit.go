@@ -0,0 +1,42 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pjadmit decides which EnqueuedState ProwJobs plank may move to
+// PendingState. This models the "queue-then-admit" pattern used by
+// batch-oriented Kubernetes schedulers, letting operators plug in gang
+// scheduling, priority-based preemption, or budget-aware admission without
+// forking plank.
+package pjadmit
+
+import "k8s.io/test-infra/prow/kube"
+
+// ResourceSnapshot describes the cluster capacity an Admitter has to work
+// with at the moment it is asked to admit a job.
+type ResourceSnapshot struct {
+	// FreeCPUMillis is the free allocatable CPU across the cluster, in
+	// millicores.
+	FreeCPUMillis int64
+	// FreeMemoryBytes is the free allocatable memory across the cluster, in
+	// bytes.
+	FreeMemoryBytes int64
+}
+
+// Admitter decides whether a single enqueued ProwJob may proceed to
+// PendingState given the current cluster capacity. reason explains a
+// refusal and is ignored when admit is true.
+type Admitter interface {
+	Admit(pj kube.ProwJob, cluster ResourceSnapshot) (admit bool, reason string)
+}
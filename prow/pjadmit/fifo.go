@@ -0,0 +1,89 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjadmit
+
+import (
+	"fmt"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// FIFOAdmitter admits enqueued ProwJobs in the order Admit is called for
+// them, honoring each job's MaxConcurrency, any quota configured for the
+// queue named in its GangSchedule, and the cluster's free CPU/memory. It accumulates what it
+// has admitted so far, so a single FIFOAdmitter should be constructed fresh
+// for each admission pass over a ResourceSnapshot.
+type FIFOAdmitter struct {
+	queueQuotas      map[string]int
+	jobConcurrency   map[string]int
+	queueConcurrency map[string]int
+	admittedCPU      int64
+	admittedMemory   int64
+}
+
+// NewFIFOAdmitter returns a FIFOAdmitter seeded with the jobs already
+// Pending or running, so their MaxConcurrency and queue quotas are honored
+// from the first Admit call of the pass. queueQuotas maps a GangSchedule
+// queue name to the maximum number of jobs from that queue allowed to run
+// at once; queues absent from the map are unlimited.
+func NewFIFOAdmitter(running []kube.ProwJob, queueQuotas map[string]int) *FIFOAdmitter {
+	a := &FIFOAdmitter{
+		queueQuotas:      queueQuotas,
+		jobConcurrency:   map[string]int{},
+		queueConcurrency: map[string]int{},
+	}
+	for _, pj := range running {
+		a.jobConcurrency[pj.Spec.Job]++
+		if gs := pj.Spec.GangSchedule; gs != nil && gs.Queue != "" {
+			a.queueConcurrency[gs.Queue]++
+		}
+	}
+	return a
+}
+
+// Admit implements Admitter.
+func (a *FIFOAdmitter) Admit(pj kube.ProwJob, cluster ResourceSnapshot) (bool, string) {
+	if pj.Spec.MaxConcurrency > 0 && a.jobConcurrency[pj.Spec.Job] >= pj.Spec.MaxConcurrency {
+		return false, fmt.Sprintf("job %q is already running %d instances, its configured max", pj.Spec.Job, pj.Spec.MaxConcurrency)
+	}
+
+	queue := ""
+	if gs := pj.Spec.GangSchedule; gs != nil {
+		queue = gs.Queue
+	}
+	if queue != "" {
+		if quota, ok := a.queueQuotas[queue]; ok && a.queueConcurrency[queue] >= quota {
+			return false, fmt.Sprintf("queue %q is already running %d jobs, its configured quota", queue, quota)
+		}
+	}
+
+	cpu, memory := podResourceRequest(pj.Spec.PodSpec)
+	if cpu > cluster.FreeCPUMillis-a.admittedCPU {
+		return false, "not enough free cluster CPU"
+	}
+	if memory > cluster.FreeMemoryBytes-a.admittedMemory {
+		return false, "not enough free cluster memory"
+	}
+
+	a.jobConcurrency[pj.Spec.Job]++
+	if queue != "" {
+		a.queueConcurrency[queue]++
+	}
+	a.admittedCPU += cpu
+	a.admittedMemory += memory
+	return true, ""
+}
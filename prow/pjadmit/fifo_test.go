@@ -0,0 +1,119 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjadmit
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestFIFOAdmitterMaxConcurrency(t *testing.T) {
+	running := []kube.ProwJob{
+		{Spec: kube.ProwJobSpec{Job: "some-job"}},
+	}
+	a := NewFIFOAdmitter(running, nil)
+	pj := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "some-job", MaxConcurrency: 1}}
+	if admit, reason := a.Admit(pj, ResourceSnapshot{FreeCPUMillis: 1000, FreeMemoryBytes: 1 << 30}); admit {
+		t.Errorf("expected admission to be refused at max concurrency, got reason %q", reason)
+	}
+}
+
+func TestFIFOAdmitterQueueQuota(t *testing.T) {
+	a := NewFIFOAdmitter(nil, map[string]int{"e2e": 1})
+	pj := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "a", GangSchedule: &kube.GangSchedule{Queue: "e2e"}}}
+	cluster := ResourceSnapshot{FreeCPUMillis: 1000, FreeMemoryBytes: 1 << 30}
+
+	admit, _ := a.Admit(pj, cluster)
+	if !admit {
+		t.Fatal("expected the first job in the queue to be admitted")
+	}
+	if admit, _ := a.Admit(pj, cluster); admit {
+		t.Error("expected the second job to be refused once the queue quota is met")
+	}
+}
+
+func TestFIFOAdmitterResourceExhaustion(t *testing.T) {
+	a := NewFIFOAdmitter(nil, nil)
+	pj := kube.ProwJob{
+		Spec: kube.ProwJobSpec{
+			Job: "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{
+					Resources: kube.ResourceRequirements{
+						Requests: map[string]string{"cpu": "2", "memory": "1Gi"},
+					},
+				}},
+			},
+		},
+	}
+	if admit, reason := a.Admit(pj, ResourceSnapshot{FreeCPUMillis: 500, FreeMemoryBytes: 1 << 30}); admit {
+		t.Errorf("expected admission to be refused for lack of CPU, got reason %q", reason)
+	}
+	if admit, reason := a.Admit(pj, ResourceSnapshot{FreeCPUMillis: 2000, FreeMemoryBytes: 1 << 30}); !admit {
+		t.Errorf("expected admission with enough resources, got refusal %q", reason)
+	}
+}
+
+func TestWeightedFairAdmitterShares(t *testing.T) {
+	base := NewFIFOAdmitter(nil, nil)
+	w := NewWeightedFairAdmitter(base, 4, map[string]int{"ci-e2e": 3, "ci-unit": 1})
+	cluster := ResourceSnapshot{FreeCPUMillis: 100000, FreeMemoryBytes: 1 << 40}
+
+	e2e := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "ci-e2e-gke"}}
+	unit := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "ci-unit-go"}}
+
+	admitted := 0
+	for i := 0; i < 3; i++ {
+		if admit, _ := w.Admit(e2e, cluster); admit {
+			admitted++
+		}
+	}
+	if admitted != 3 {
+		t.Errorf("expected ci-e2e's 3/4 share to admit 3 jobs, admitted %d", admitted)
+	}
+	if admit, _ := w.Admit(e2e, cluster); admit {
+		t.Error("expected a 4th ci-e2e job to be refused once its share is exhausted")
+	}
+	if admit, reason := w.Admit(unit, cluster); !admit {
+		t.Errorf("expected ci-unit's own share to still admit, got refusal %q", reason)
+	}
+}
+
+func TestWeightedFairAdmitterUnlistedFamilyCountsTowardTotalWeight(t *testing.T) {
+	base := NewFIFOAdmitter(nil, nil)
+	w := NewWeightedFairAdmitter(base, 4, map[string]int{"ci-e2e": 3})
+	cluster := ResourceSnapshot{FreeCPUMillis: 100000, FreeMemoryBytes: 1 << 40}
+
+	e2e := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "ci-e2e-gke"}}
+	lint := kube.ProwJob{Spec: kube.ProwJobSpec{Job: "ci-lint-go"}}
+
+	admitted := 0
+	for i := 0; i < w.Capacity; i++ {
+		if admit, _ := w.Admit(lint, cluster); admit {
+			admitted++
+		}
+	}
+	for i := 0; i < w.Capacity; i++ {
+		if admit, _ := w.Admit(e2e, cluster); admit {
+			admitted++
+		}
+	}
+	if admitted > w.Capacity {
+		t.Errorf("expected ci-e2e's and unlisted ci-lint's shares to sum to at most Capacity %d, admitted %d", w.Capacity, admitted)
+	}
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjadmit
+
+import (
+	"strconv"
+	"strings"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// parseCPUMillis parses the handful of CPU quantity suffixes Prow configs
+// actually use ("500m", "2") into millicores. It is not a full
+// resource.Quantity parser; unparseable values are treated as zero.
+func parseCPUMillis(qty string) int64 {
+	if qty == "" {
+		return 0
+	}
+	if strings.HasSuffix(qty, "m") {
+		v, _ := strconv.ParseInt(strings.TrimSuffix(qty, "m"), 10, 64)
+		return v
+	}
+	v, _ := strconv.ParseFloat(qty, 64)
+	return int64(v * 1000)
+}
+
+// parseMemoryBytes parses the handful of memory quantity suffixes Prow
+// configs actually use ("512Mi", "1Gi", "128Ki") into bytes. It is not a
+// full resource.Quantity parser; unparseable values are treated as zero.
+func parseMemoryBytes(qty string) int64 {
+	if qty == "" {
+		return 0
+	}
+	suffixes := map[string]int64{
+		"Ki": 1 << 10,
+		"Mi": 1 << 20,
+		"Gi": 1 << 30,
+		"Ti": 1 << 40,
+	}
+	for suffix, multiplier := range suffixes {
+		if strings.HasSuffix(qty, suffix) {
+			v, _ := strconv.ParseFloat(strings.TrimSuffix(qty, suffix), 64)
+			return int64(v * float64(multiplier))
+		}
+	}
+	v, _ := strconv.ParseFloat(qty, 64)
+	return int64(v)
+}
+
+// podResourceRequest sums the CPU and memory requests of every container in
+// spec, as a rough estimate of what the resulting Pod will consume.
+func podResourceRequest(spec kube.PodSpec) (cpuMillis, memoryBytes int64) {
+	for _, c := range spec.Containers {
+		cpuMillis += parseCPUMillis(c.Resources.Requests["cpu"])
+		memoryBytes += parseMemoryBytes(c.Resources.Requests["memory"])
+	}
+	return cpuMillis, memoryBytes
+}
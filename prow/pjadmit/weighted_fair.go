@@ -0,0 +1,120 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjadmit
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// WeightedFairAdmitter wraps another Admitter (typically a FIFOAdmitter, to
+// also honor MaxConcurrency and cluster resources) and additionally
+// partitions a fixed admission Capacity per pass across ProwJob "families" —
+// identified by the portion of Spec.Job up to its last "-" — so that no
+// single family can starve the others. A family's share of Capacity is
+// proportional to its entry in Weights; families absent from Weights get a
+// weight of 1.
+type WeightedFairAdmitter struct {
+	Next     Admitter
+	Capacity int
+	Weights  map[string]int
+
+	admittedByFamily map[string]int
+	seenFamilies     map[string]bool
+}
+
+// NewWeightedFairAdmitter returns a WeightedFairAdmitter that divides
+// capacity admissions per pass among job families, delegating the final
+// admit decision for any job within its family's share to next. There is no
+// overall cap: the total admitted can exceed capacity, because every family
+// gets at least one slot and a family absent from weights only counts
+// toward the shares once a job of it has been seen.
+func NewWeightedFairAdmitter(next Admitter, capacity int, weights map[string]int) *WeightedFairAdmitter {
+	return &WeightedFairAdmitter{
+		Next:             next,
+		Capacity:         capacity,
+		Weights:          weights,
+		admittedByFamily: map[string]int{},
+		seenFamilies:     map[string]bool{},
+	}
+}
+
+// jobFamily returns the prefix of job up to (and not including) its last
+// "-", or job itself if it has none.
+func jobFamily(job string) string {
+	if i := strings.LastIndex(job, "-"); i >= 0 {
+		return job[:i]
+	}
+	return job
+}
+
+// Admit implements Admitter.
+func (w *WeightedFairAdmitter) Admit(pj kube.ProwJob, cluster ResourceSnapshot) (bool, string) {
+	family := jobFamily(pj.Spec.Job)
+	w.seenFamilies[family] = true
+	share := w.familyShare(family)
+	if w.admittedByFamily[family] >= share {
+		return false, fmt.Sprintf("job family %q is at its fair-share quota of %d for this pass", family, share)
+	}
+
+	admit, reason := w.Next.Admit(pj, cluster)
+	if !admit {
+		return false, reason
+	}
+	w.admittedByFamily[family]++
+	return true, ""
+}
+
+// familyShare computes how many of Capacity's admissions this pass a family
+// is entitled to, proportional to its weight (default 1) among all known
+// weights, with a floor of one slot so a configured family is never fully
+// starved. totalWeight must count every family competing for Capacity, not
+// just the ones explicitly listed in Weights, or the shares handed to
+// unlisted families would be computed against too small a denominator and
+// their sum could exceed Capacity. Families outside Weights only become
+// known to the admitter as jobs for them are Admitted, so totalWeight adds
+// their default weight of 1 in as they're seen this pass; shares handed out
+// before such a family was first seen did not count it.
+func (w *WeightedFairAdmitter) familyShare(family string) int {
+	weight := w.Weights[family]
+	if weight <= 0 {
+		weight = 1
+	}
+	totalWeight := 0
+	for _, wt := range w.Weights {
+		if wt > 0 {
+			totalWeight += wt
+		} else {
+			totalWeight++
+		}
+	}
+	for fam := range w.seenFamilies {
+		if _, explicit := w.Weights[fam]; !explicit {
+			totalWeight++
+		}
+	}
+	if totalWeight == 0 {
+		totalWeight = 1
+	}
+	share := w.Capacity * weight / totalWeight
+	if share < 1 {
+		share = 1
+	}
+	return share
+}
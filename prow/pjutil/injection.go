@@ -0,0 +1,177 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+// Well-known annotation prefixes that let a ProwJob be triggered with
+// per-invocation parameters (extra env, resource bumps, node targeting)
+// without editing the central Prow config. ProwJobToPod applies these to
+// the generated Pod; the ProwJob spec itself is left untouched.
+const (
+	injectEnvPrefix      = "prow.k8s.io/inject-env."
+	injectResourcePrefix = "prow.k8s.io/inject-resource."
+	nodeSelectorPrefix   = "prow.k8s.io/node-selector."
+	tolerationPrefix     = "prow.k8s.io/toleration"
+)
+
+// Injections is the parsed form of a ProwJob's injection annotations.
+type Injections struct {
+	// Env maps environment variable name to value, applied to every
+	// container in the generated Pod.
+	Env map[string]string
+	// Resources maps a container selector (its index, e.g. "0", or its
+	// generated name, e.g. "<pjname>-0") to resource requirements that are
+	// merged into that container's existing requirements.
+	Resources map[string]kube.ResourceRequirements
+	// NodeSelector is merged into the Pod's node selector.
+	NodeSelector map[string]string
+	// Tolerations are appended to the Pod's tolerations.
+	Tolerations []kube.Toleration
+}
+
+// ParseInjectionAnnotations extracts the well-known injection annotations
+// (prow.k8s.io/inject-env.*, prow.k8s.io/inject-resource.*,
+// prow.k8s.io/node-selector.*, prow.k8s.io/toleration and
+// prow.k8s.io/toleration.*) from a ProwJob's
+// annotations into a typed Injections. It returns an error if any
+// recognized annotation is malformed.
+func ParseInjectionAnnotations(annotations map[string]string) (Injections, error) {
+	injections := Injections{
+		Env:          map[string]string{},
+		Resources:    map[string]kube.ResourceRequirements{},
+		NodeSelector: map[string]string{},
+	}
+	for key, value := range annotations {
+		switch {
+		case strings.HasPrefix(key, injectEnvPrefix):
+			name := strings.TrimPrefix(key, injectEnvPrefix)
+			if name == "" {
+				return Injections{}, fmt.Errorf("%s: missing environment variable name", key)
+			}
+			injections.Env[name] = value
+		case strings.HasPrefix(key, injectResourcePrefix):
+			if err := injections.addResource(key, value); err != nil {
+				return Injections{}, err
+			}
+		case strings.HasPrefix(key, nodeSelectorPrefix):
+			name := strings.TrimPrefix(key, nodeSelectorPrefix)
+			if name == "" {
+				return Injections{}, fmt.Errorf("%s: missing node selector key", key)
+			}
+			injections.NodeSelector[name] = value
+		case key == tolerationPrefix || strings.HasPrefix(key, tolerationPrefix+"."):
+			var toleration kube.Toleration
+			if err := json.Unmarshal([]byte(value), &toleration); err != nil {
+				return Injections{}, fmt.Errorf("%s: invalid toleration %q: %v", key, value, err)
+			}
+			injections.Tolerations = append(injections.Tolerations, toleration)
+		}
+	}
+	return injections, nil
+}
+
+// addResource parses a single prow.k8s.io/inject-resource.<container>.<field>
+// annotation into injections.Resources. field is either a request name
+// (cpu, memory, ...) or prefixed with "limits." for a limit.
+func (i *Injections) addResource(key, value string) error {
+	rest := strings.TrimPrefix(key, injectResourcePrefix)
+	parts := strings.SplitN(rest, ".", 2)
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return fmt.Errorf("%s: expected prow.k8s.io/inject-resource.<container>.<field>", key)
+	}
+	container, field := parts[0], parts[1]
+	req := i.Resources[container]
+	if strings.HasPrefix(field, "limits.") {
+		if req.Limits == nil {
+			req.Limits = map[string]string{}
+		}
+		req.Limits[strings.TrimPrefix(field, "limits.")] = value
+	} else {
+		if req.Requests == nil {
+			req.Requests = map[string]string{}
+		}
+		req.Requests[field] = value
+	}
+	i.Resources[container] = req
+	return nil
+}
+
+// ApplyTo merges the injections into spec: env vars into every container,
+// resources into the container matched by index or generated name, and
+// node selector/tolerations onto the Pod as a whole. It does not mutate any
+// ProwJob spec; callers apply it to a copy of a Pod's PodSpec.
+func (i Injections) ApplyTo(spec *kube.PodSpec) {
+	for idx := range spec.Containers {
+		c := &spec.Containers[idx]
+		for name, value := range i.Env {
+			c.Env = append(c.Env, kube.EnvVar{Name: name, Value: value})
+		}
+		if req, ok := i.Resources[strconv.Itoa(idx)]; ok {
+			mergeResources(&c.Resources, req)
+		}
+		if req, ok := i.Resources[c.Name]; ok {
+			mergeResources(&c.Resources, req)
+		}
+	}
+	if len(i.NodeSelector) > 0 {
+		nodeSelector := map[string]string{}
+		for k, v := range spec.NodeSelector {
+			nodeSelector[k] = v
+		}
+		for k, v := range i.NodeSelector {
+			nodeSelector[k] = v
+		}
+		spec.NodeSelector = nodeSelector
+	}
+	spec.Tolerations = append(spec.Tolerations, i.Tolerations...)
+}
+
+// mergeResources copies any requests/limits set in src into dst, leaving
+// the container's own values in place for fields src does not set. dst's
+// existing maps are never written to directly: they may be shared with
+// other containers built from the same config, so merged fields land in
+// freshly allocated maps.
+func mergeResources(dst *kube.ResourceRequirements, src kube.ResourceRequirements) {
+	if len(src.Requests) > 0 {
+		requests := map[string]string{}
+		for k, v := range dst.Requests {
+			requests[k] = v
+		}
+		for k, v := range src.Requests {
+			requests[k] = v
+		}
+		dst.Requests = requests
+	}
+	if len(src.Limits) > 0 {
+		limits := map[string]string{}
+		for k, v := range dst.Limits {
+			limits[k] = v
+		}
+		for k, v := range src.Limits {
+			limits[k] = v
+		}
+		dst.Limits = limits
+	}
+}
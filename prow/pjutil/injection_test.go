@@ -0,0 +1,160 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestParseInjectionAnnotationsMalformed(t *testing.T) {
+	cases := []struct {
+		name        string
+		annotations map[string]string
+	}{
+		{
+			name:        "empty env var name",
+			annotations: map[string]string{"prow.k8s.io/inject-env.": "val"},
+		},
+		{
+			name:        "resource annotation missing field",
+			annotations: map[string]string{"prow.k8s.io/inject-resource.main": "1"},
+		},
+		{
+			name:        "empty node selector key",
+			annotations: map[string]string{"prow.k8s.io/node-selector.": "val"},
+		},
+		{
+			name:        "invalid toleration json",
+			annotations: map[string]string{"prow.k8s.io/toleration": "not-json"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := ParseInjectionAnnotations(tc.annotations); err == nil {
+				t.Error("expected an error, got none")
+			}
+		})
+	}
+}
+
+func TestParseInjectionAnnotations(t *testing.T) {
+	annotations := map[string]string{
+		"prow.k8s.io/inject-env.FOO":               "bar",
+		"prow.k8s.io/inject-resource.0.cpu":        "2",
+		"prow.k8s.io/inject-resource.0.limits.cpu": "4",
+		"prow.k8s.io/node-selector.disktype":       "ssd",
+		"prow.k8s.io/toleration":                   `{"key":"dedicated","operator":"Equal","value":"batch","effect":"NoSchedule"}`,
+		"unrelated-annotation":                     "ignored",
+	}
+	injections, err := ParseInjectionAnnotations(annotations)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if injections.Env["FOO"] != "bar" {
+		t.Errorf("expected injected env FOO=bar, got %q", injections.Env["FOO"])
+	}
+	if got := injections.Resources["0"].Requests["cpu"]; got != "2" {
+		t.Errorf("expected cpu request 2, got %q", got)
+	}
+	if got := injections.Resources["0"].Limits["cpu"]; got != "4" {
+		t.Errorf("expected cpu limit 4, got %q", got)
+	}
+	if injections.NodeSelector["disktype"] != "ssd" {
+		t.Errorf("expected node selector disktype=ssd, got %q", injections.NodeSelector["disktype"])
+	}
+	if len(injections.Tolerations) != 1 || injections.Tolerations[0].Key != "dedicated" {
+		t.Errorf("expected one dedicated toleration, got %+v", injections.Tolerations)
+	}
+}
+
+func TestProwJobToPodInjectsAnnotations(t *testing.T) {
+	pj := kube.ProwJob{
+		Metadata: kube.ObjectMeta{
+			Name: "some-job",
+			Annotations: map[string]string{
+				"prow.k8s.io/inject-env.FOO":         "bar",
+				"prow.k8s.io/inject-resource.0.cpu":  "2",
+				"prow.k8s.io/node-selector.disktype": "ssd",
+			},
+		},
+		Spec: kube.ProwJobSpec{
+			Type: kube.PeriodicJob,
+			Job:  "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+		},
+	}
+	pods, _, _, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := pods[0]
+	found := false
+	for _, ev := range pod.Spec.Containers[0].Env {
+		if ev.Name == "FOO" && ev.Value == "bar" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected injected FOO=bar env var on the generated container")
+	}
+	if got := pod.Spec.Containers[0].Resources.Requests["cpu"]; got != "2" {
+		t.Errorf("expected injected cpu request 2, got %q", got)
+	}
+	if pod.Spec.NodeSelector["disktype"] != "ssd" {
+		t.Errorf("expected injected node selector, got %v", pod.Spec.NodeSelector)
+	}
+
+	// Calling ProwJobToPod again on the same ProwJob must be idempotent.
+	pods2, _, _, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error on second call: %v", err)
+	}
+	if len(pods2[0].Spec.Containers[0].Env) != len(pod.Spec.Containers[0].Env) {
+		t.Errorf("expected idempotent env injection, got %d then %d entries",
+			len(pod.Spec.Containers[0].Env), len(pods2[0].Spec.Containers[0].Env))
+	}
+}
+
+func TestProwJobToPodInjectsByGeneratedContainerName(t *testing.T) {
+	pj := kube.ProwJob{
+		Metadata: kube.ObjectMeta{
+			Name: "some-job",
+			Annotations: map[string]string{
+				"prow.k8s.io/inject-resource.some-job-0.memory": "1Gi",
+			},
+		},
+		Spec: kube.ProwJobSpec{
+			Type: kube.PeriodicJob,
+			Job:  "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+		},
+	}
+	pods, _, _, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := pods[0]
+	if got := pod.Spec.Containers[0].Resources.Requests["memory"]; got != "1Gi" {
+		t.Errorf("expected injected memory request 1Gi, got %q", got)
+	}
+}
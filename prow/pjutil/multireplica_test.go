@@ -0,0 +1,109 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"fmt"
+	"testing"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+func multiReplicaProwJob(replicas int) kube.ProwJob {
+	return kube.ProwJob{
+		Metadata: kube.ObjectMeta{Name: "some-job", Namespace: "prow-jobs"},
+		Spec: kube.ProwJobSpec{
+			Type:     kube.MultiReplicaJob,
+			Job:      "some-job",
+			Replicas: replicas,
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+		},
+	}
+}
+
+func TestProwJobToPodSingleReplicaBackwardsCompatible(t *testing.T) {
+	pj := multiReplicaProwJob(1)
+	pods, _, service, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected exactly one pod, got %d", len(pods))
+	}
+	if service != nil {
+		t.Errorf("expected no Service for a single-replica job, got %+v", service)
+	}
+	if pods[0].Metadata.Name != "some-job" {
+		t.Errorf("expected unsuffixed pod name %q, got %q", "some-job", pods[0].Metadata.Name)
+	}
+	if pods[0].Metadata.Labels[kube.ProwJobReplicaIndexLabel] != "" {
+		t.Error("expected no replica-index label for a single-replica job")
+	}
+}
+
+func TestProwJobToPodMultiReplica(t *testing.T) {
+	pj := multiReplicaProwJob(3)
+	pods, _, service, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 3 {
+		t.Fatalf("expected 3 pods, got %d", len(pods))
+	}
+	if service == nil {
+		t.Fatal("expected a headless Service for a multi-replica job")
+	}
+	if service.Metadata.Name != "some-job" {
+		t.Errorf("expected Service name %q, got %q", "some-job", service.Metadata.Name)
+	}
+	if service.Spec.ClusterIP != "None" {
+		t.Errorf("expected a headless Service, got ClusterIP %q", service.Spec.ClusterIP)
+	}
+	if service.Spec.Selector[kube.ProwJobNameLabel] != "some-job" {
+		t.Errorf("expected the Service to select %s=some-job, got %v", kube.ProwJobNameLabel, service.Spec.Selector)
+	}
+
+	for i, pod := range pods {
+		wantName := fmt.Sprintf("some-job-%d", i)
+		if pod.Metadata.Name != wantName {
+			t.Errorf("pod %d: expected name %q, got %q", i, wantName, pod.Metadata.Name)
+		}
+		if pod.Metadata.Labels[kube.ProwJobReplicaIndexLabel] != fmt.Sprintf("%d", i) {
+			t.Errorf("pod %d: expected replica-index label %d, got %q", i, i, pod.Metadata.Labels[kube.ProwJobReplicaIndexLabel])
+		}
+		if pod.Metadata.Labels[kube.ProwJobNameLabel] != "some-job" {
+			t.Errorf("pod %d: expected prowjob-name label %q, got %q", i, "some-job", pod.Metadata.Labels[kube.ProwJobNameLabel])
+		}
+
+		env := map[string]string{}
+		for _, ev := range pod.Spec.Containers[0].Env {
+			env[ev.Name] = ev.Value
+		}
+		if env["JOB_COMPLETION_INDEX"] != fmt.Sprintf("%d", i) {
+			t.Errorf("pod %d: expected JOB_COMPLETION_INDEX=%d, got %q", i, i, env["JOB_COMPLETION_INDEX"])
+		}
+		if env["JOB_NUM_REPLICAS"] != "3" {
+			t.Errorf("pod %d: expected JOB_NUM_REPLICAS=3, got %q", i, env["JOB_NUM_REPLICAS"])
+		}
+		wantCoordinator := "some-job-0.some-job.prow-jobs.svc.cluster.local"
+		if env["JOB_COORDINATOR_ADDRESS"] != wantCoordinator {
+			t.Errorf("pod %d: expected JOB_COORDINATOR_ADDRESS=%q, got %q", i, wantCoordinator, env["JOB_COORDINATOR_ADDRESS"])
+		}
+	}
+}
@@ -18,6 +18,8 @@ limitations under the License.
 package pjutil
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
 	"strconv"
 	"time"
@@ -28,8 +30,56 @@ import (
 	"k8s.io/test-infra/prow/kube"
 )
 
-// NewProwJob initializes a ProwJob out of a ProwJobSpec.
-func NewProwJob(spec kube.ProwJobSpec, labels map[string]string) kube.ProwJob {
+// PrebuiltProwJobLabel, when present on the labels passed to NewProwJob,
+// gives the Metadata.Name to use for the ProwJob instead of minting a
+// fresh UUID. It lets a caller that reserved a name ahead of trigger time
+// (before all of a job's Refs were known) produce a ProwJob keyed by that
+// reserved name.
+const PrebuiltProwJobLabel = "prow.k8s.io/prebuilt-prowjob-name"
+
+// PrebuiltProwJobGetter looks up the ProwJob previously reserved under
+// name, returning ok false if no such reservation exists. Systems that
+// reserve a slot ahead of trigger time (tide, a pre-warmed job cache, IDE
+// integrations) implement one backed by their own store — this package
+// keeps no client or lister of its own — and pass it to NewProwJob so
+// triggering can bind to the actual reserved object instead of fabricating
+// a new one that merely shares its name.
+type PrebuiltProwJobGetter func(name string) (kube.ProwJob, bool)
+
+// NewProwJob initializes a ProwJob out of a ProwJobSpec. The job starts out
+// EnqueuedState; it is plank's job, via a pjadmit.Admitter, to move it to
+// PendingState once cluster capacity and any queue quotas allow it to run.
+//
+// If labels carries PrebuiltProwJobLabel and get resolves it to a
+// previously reserved ProwJob, that ProwJob's own Name, Status, and
+// StartTime are retained; its Spec is replaced with the caller's spec
+// (which, unlike the reservation's, has refs and other trigger-time
+// details filled in) and labels are merged in on top of whatever it
+// already carried. get may be nil, in which case (or if the lookup
+// misses) NewProwJob falls back to PrebuiltProwJob, which only fabricates
+// a shell keyed by the label; see its doc comment for the caveat that
+// implies.
+func NewProwJob(spec kube.ProwJobSpec, labels map[string]string, get PrebuiltProwJobGetter) kube.ProwJob {
+	if label := labels[PrebuiltProwJobLabel]; label != "" && get != nil {
+		if pj, ok := get(label); ok {
+			mergedLabels := make(map[string]string, len(pj.Metadata.Labels)+len(labels))
+			for k, v := range pj.Metadata.Labels {
+				mergedLabels[k] = v
+			}
+			for k, v := range labels {
+				mergedLabels[k] = v
+			}
+			pj.Metadata.Labels = mergedLabels
+			pj.Spec = spec
+			return pj
+		}
+	}
+	if pj, ok := PrebuiltProwJob(labels[PrebuiltProwJobLabel], spec); ok {
+		for k, v := range labels {
+			pj.Metadata.Labels[k] = v
+		}
+		return pj
+	}
 	return kube.ProwJob{
 		APIVersion: "prow.k8s.io/v1",
 		Kind:       "ProwJob",
@@ -40,11 +90,52 @@ func NewProwJob(spec kube.ProwJobSpec, labels map[string]string) kube.ProwJob {
 		Spec: spec,
 		Status: kube.ProwJobStatus{
 			StartTime: time.Now(),
-			State:     kube.TriggeredState,
+			State:     kube.EnqueuedState,
 		},
 	}
 }
 
+// PrebuiltProwJob builds a fresh ProwJob named label instead of a random
+// UUID, for systems such as tide, a pre-warmed job cache, or IDE
+// integrations that reserve a name ahead of trigger time (before all refs
+// are known) and want every ProwJob created under that name to share it.
+// It is NewProwJob's fallback when no PrebuiltProwJobGetter is supplied, or
+// the getter doesn't recognize the name: it always builds a new object,
+// with a fresh StartTime and EnqueuedState, carrying only label and spec,
+// rather than recovering the state of any ProwJob previously reserved
+// under label. Callers that need the reservation's actual labels or state
+// preserved must supply a PrebuiltProwJobGetter to NewProwJob instead. ok
+// is false when label is empty, in which case callers should mint a new
+// ProwJob instead.
+func PrebuiltProwJob(label string, spec kube.ProwJobSpec) (kube.ProwJob, bool) {
+	if label == "" {
+		return kube.ProwJob{}, false
+	}
+	return kube.ProwJob{
+		APIVersion: "prow.k8s.io/v1",
+		Kind:       "ProwJob",
+		Metadata: kube.ObjectMeta{
+			Name:   label,
+			Labels: map[string]string{PrebuiltProwJobLabel: label},
+		},
+		Spec: spec,
+		Status: kube.ProwJobStatus{
+			StartTime: time.Now(),
+			State:     kube.EnqueuedState,
+		},
+	}, true
+}
+
+// PrebuiltKey deterministically identifies a ProwJobSpec by the fields that
+// distinguish one job invocation from another: its Job name, Type, and
+// Refs. Callers use it to look up an existing prebuilt ProwJob shell for a
+// spec, so that identical batch jobs triggered for overlapping PR sets can
+// be deduplicated onto the same slot.
+func PrebuiltKey(spec kube.ProwJobSpec) string {
+	sum := sha256.Sum256([]byte(fmt.Sprintf("%s|%s|%s/%s|%s", spec.Job, spec.Type, spec.Refs.Org, spec.Refs.Repo, spec.Refs.String())))
+	return hex.EncodeToString(sum[:])
+}
+
 // PresubmitSpec initializes a ProwJobSpec for a given presubmit job.
 func PresubmitSpec(p config.Presubmit, refs kube.Refs) kube.ProwJobSpec {
 	pjs := kube.ProwJobSpec{
@@ -119,10 +210,80 @@ func BatchSpec(p config.Presubmit, refs kube.Refs) kube.ProwJobSpec {
 	return pjs
 }
 
-// ProwJobToPod converts a ProwJob to a Pod that will run the tests.
-func ProwJobToPod(pj kube.ProwJob, buildID string) *kube.Pod {
+// MultiReplicaSpec initializes a ProwJobSpec for a given multi-replica job.
+func MultiReplicaSpec(m config.MultiReplica, refs kube.Refs) kube.ProwJobSpec {
+	pjs := kube.ProwJobSpec{
+		Type:     kube.MultiReplicaJob,
+		Job:      m.Name,
+		Refs:     refs,
+		Replicas: m.Replicas,
+	}
+	pjs.Agent = kube.ProwJobAgent(m.Agent)
+	if pjs.Agent == kube.KubernetesAgent {
+		pjs.PodSpec = *m.Spec
+	}
+	for _, nextM := range m.RunAfterSuccess {
+		pjs.RunAfterSuccess = append(pjs.RunAfterSuccess, MultiReplicaSpec(nextM, refs))
+	}
+	return pjs
+}
+
+// ProwJobToPod converts a ProwJob into the Pod(s) that will run its tests:
+// one, unless the ProwJob is a MultiReplicaJob with more than one replica,
+// in which case one per replica plus a headless Service so the replicas can
+// discover each other. If the ProwJob requests gang scheduling, it also
+// returns the PodGroup to create before the Pods, so that the batch
+// scheduler can withhold starting any of them until the whole gang is
+// admitted. It returns an error if the ProwJob carries malformed injection
+// annotations.
+func ProwJobToPod(pj kube.ProwJob, buildID string) ([]*kube.Pod, *kube.PodGroup, *kube.Service, error) {
+	injections, err := ParseInjectionAnnotations(pj.Metadata.Annotations)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("parsing injection annotations: %v", err)
+	}
+
+	replicas := pj.Spec.Replicas
+	if replicas < 1 {
+		replicas = 1
+	}
+
+	pods := make([]*kube.Pod, 0, replicas)
+	for replica := 0; replica < replicas; replica++ {
+		pods = append(pods, prowJobToPod(pj, buildID, replica, replicas, injections))
+	}
+
+	var podGroup *kube.PodGroup
+	if gs := pj.Spec.GangSchedule; gs != nil {
+		podGroup = gangPodGroup(pj, gs)
+	}
+
+	var service *kube.Service
+	if replicas > 1 {
+		service = replicaService(pj)
+	}
+
+	return pods, podGroup, service, nil
+}
+
+// prowJobToPod builds the Pod for a single replica of pj. podName is
+// pj.Metadata.Name itself when there is only one replica, preserving the
+// pre-multi-replica naming and environment exactly; with more than one
+// replica it becomes "<pjname>-<replica>" and each pod additionally learns
+// its place in the group via JOB_COMPLETION_INDEX, JOB_NUM_REPLICAS, and
+// JOB_COORDINATOR_ADDRESS.
+func prowJobToPod(pj kube.ProwJob, buildID string, replica, replicas int, injections Injections) *kube.Pod {
+	podName := pj.Metadata.Name
+	if replicas > 1 {
+		podName = fmt.Sprintf("%s-%d", pj.Metadata.Name, replica)
+	}
+
 	env := EnvForSpec(pj.Spec)
 	env["BUILD_NUMBER"] = buildID
+	if replicas > 1 {
+		for k, v := range envForReplica(pj, replica, replicas) {
+			env[k] = v
+		}
+	}
 
 	spec := pj.Spec.PodSpec
 	spec.RestartPolicy = "Never"
@@ -133,27 +294,106 @@ func ProwJobToPod(pj kube.ProwJob, buildID string) *kube.Pod {
 	spec.Containers = []kube.Container{}
 	for i := range pj.Spec.PodSpec.Containers {
 		spec.Containers = append(spec.Containers, pj.Spec.PodSpec.Containers[i])
-		spec.Containers[i].Name = fmt.Sprintf("%s-%d", pj.Metadata.Name, i)
+		spec.Containers[i].Name = fmt.Sprintf("%s-%d", podName, i)
 		spec.Containers[i].Env = append(spec.Containers[i].Env, kubeEnv(env)...)
 	}
+
 	podLabels := make(map[string]string)
 	for k, v := range pj.Metadata.Labels {
 		podLabels[k] = v
 	}
 	podLabels[kube.CreatedByProw] = "true"
 	podLabels[kube.ProwJobTypeLabel] = string(pj.Spec.Type)
+	if replicas > 1 {
+		podLabels[kube.ProwJobNameLabel] = pj.Metadata.Name
+		podLabels[kube.ProwJobReplicaIndexLabel] = strconv.Itoa(replica)
+	}
+
+	podAnnotations := map[string]string{
+		kube.ProwJobAnnotation: pj.Spec.Job,
+	}
+
+	if gs := pj.Spec.GangSchedule; gs != nil {
+		podAnnotations[kube.GangScheduleGroupNameAnnotation] = pj.Metadata.Name
+		spec.SchedulerName = gs.SchedulerName
+		if spec.SchedulerName == "" {
+			spec.SchedulerName = kube.DefaultGangSchedulerName
+		}
+		spec.PriorityClassName = gs.PriorityClassName
+	}
+
+	injections.ApplyTo(&spec)
+
 	return &kube.Pod{
 		Metadata: kube.ObjectMeta{
-			Name:   pj.Metadata.Name,
-			Labels: podLabels,
-			Annotations: map[string]string{
-				kube.ProwJobAnnotation: pj.Spec.Job,
-			},
+			Name:        podName,
+			Namespace:   pj.Metadata.Namespace,
+			Labels:      podLabels,
+			Annotations: podAnnotations,
 		},
 		Spec: spec,
 	}
 }
 
+// envForReplica returns the environment prowJobToPod adds, on top of
+// EnvForSpec's, to each pod of a job with more than one replica (EnvForSpec
+// itself does not add it): every pod in the group learns its own index, how
+// many replicas exist in total, and the address of replica 0, which serves
+// as the coordinator for harnesses like JAX, MPI, and PyTorch that need one.
+func envForReplica(pj kube.ProwJob, replica, replicas int) map[string]string {
+	return map[string]string{
+		"JOB_COMPLETION_INDEX":    strconv.Itoa(replica),
+		"JOB_NUM_REPLICAS":        strconv.Itoa(replicas),
+		"JOB_COORDINATOR_ADDRESS": fmt.Sprintf("%s-0.%s.%s.svc.cluster.local", pj.Metadata.Name, pj.Metadata.Name, pj.Metadata.Namespace),
+	}
+}
+
+// replicaService builds the headless Service that lets a MultiReplicaJob's
+// pods discover each other by name, e.g.
+// "<pjname>-0.<pjname>.<ns>.svc.cluster.local".
+func replicaService(pj kube.ProwJob) *kube.Service {
+	return &kube.Service{
+		Metadata: kube.ObjectMeta{
+			Name:      pj.Metadata.Name,
+			Namespace: pj.Metadata.Namespace,
+		},
+		Spec: kube.ServiceSpec{
+			ClusterIP: "None",
+			Selector: map[string]string{
+				kube.ProwJobNameLabel: pj.Metadata.Name,
+			},
+		},
+	}
+}
+
+// gangPodGroup builds the PodGroup that a batch scheduler uses to admit a
+// gang-scheduled ProwJob's pod. Its lifecycle mirrors the ProwJob: it is
+// created alongside it and needs no further reconciliation once the pod has
+// started.
+func gangPodGroup(pj kube.ProwJob, gs *kube.GangSchedule) *kube.PodGroup {
+	labels := make(map[string]string)
+	for k, v := range pj.Metadata.Labels {
+		labels[k] = v
+	}
+	minResources := make(map[string]string)
+	for k, v := range gs.MinResources {
+		minResources[k] = v
+	}
+	return &kube.PodGroup{
+		APIVersion: kube.PodGroupAPIVersion,
+		Kind:       "PodGroup",
+		Metadata: kube.ObjectMeta{
+			Name:   pj.Metadata.Name,
+			Labels: labels,
+		},
+		Spec: kube.PodGroupSpec{
+			MinMember:    gs.MinMember,
+			MinResources: minResources,
+			Queue:        gs.Queue,
+		},
+	}
+}
+
 // kubeEnv transforms a mapping of environment variables
 // into their serialized form for a PodSpec
 func kubeEnv(environment map[string]string) []kube.EnvVar {
@@ -184,7 +424,7 @@ func EnvForSpec(spec kube.ProwJobSpec) map[string]string {
 	env["PULL_BASE_SHA"] = spec.Refs.BaseSHA
 	env["PULL_REFS"] = spec.Refs.String()
 
-	if spec.Type == kube.PostsubmitJob || spec.Type == kube.BatchJob {
+	if spec.Type == kube.PostsubmitJob || spec.Type == kube.BatchJob || spec.Type == kube.MultiReplicaJob {
 		return env
 	}
 	env["PULL_NUMBER"] = strconv.Itoa(spec.Refs.Pulls[0].Number)
@@ -192,33 +432,42 @@ func EnvForSpec(spec kube.ProwJobSpec) map[string]string {
 	return env
 }
 
-// PartitionPending separates the provided prowjobs into pending and non-pending
-// and returns them inside channels so that they can be consumed in parallel
-// by different goroutines. Controller loops need to handle pending jobs first
-// so they can conform to maximum concurrency requirements that different jobs
-// may have.
-func PartitionPending(pjs []kube.ProwJob) (pending, nonPending chan kube.ProwJob) {
-	// Determine pending job size in order to size the channels correctly.
-	pendingCount := 0
+// PartitionPending separates the provided prowjobs into pending, enqueued,
+// and everything else, and returns them inside channels so that they can be
+// consumed in parallel by different goroutines. Controller loops need to
+// handle pending jobs first so they can conform to maximum concurrency
+// requirements that different jobs may have, and drive enqueued jobs
+// through admission separately so pod reconciliation isn't blocked on it.
+func PartitionPending(pjs []kube.ProwJob) (pending, enqueued, nonPending chan kube.ProwJob) {
+	// Determine each partition's size in order to size the channels correctly.
+	var pendingCount, enqueuedCount int
 	for _, pj := range pjs {
-		if pj.Status.State == kube.PendingState {
+		switch pj.Status.State {
+		case kube.PendingState:
 			pendingCount++
+		case kube.EnqueuedState:
+			enqueuedCount++
 		}
 	}
 	pending = make(chan kube.ProwJob, pendingCount)
-	nonPending = make(chan kube.ProwJob, len(pjs)-pendingCount)
+	enqueued = make(chan kube.ProwJob, enqueuedCount)
+	nonPending = make(chan kube.ProwJob, len(pjs)-pendingCount-enqueuedCount)
 
-	// Partition the jobs into the two separate channels.
+	// Partition the jobs into the three separate channels.
 	for _, pj := range pjs {
-		if pj.Status.State == kube.PendingState {
+		switch pj.Status.State {
+		case kube.PendingState:
 			pending <- pj
-		} else {
+		case kube.EnqueuedState:
+			enqueued <- pj
+		default:
 			nonPending <- pj
 		}
 	}
 	close(pending)
+	close(enqueued)
 	close(nonPending)
-	return pending, nonPending
+	return pending, enqueued, nonPending
 }
 
 // GetLatestPeriodics filters through the provided prowjobs and returns
@@ -0,0 +1,272 @@
+/*
+Copyright 2017 The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pjutil
+
+import (
+	"testing"
+
+	"k8s.io/test-infra/prow/kube"
+)
+
+func TestNewProwJobFallsBackToUUID(t *testing.T) {
+	pj := NewProwJob(kube.ProwJobSpec{Job: "some-job"}, nil, nil)
+	if pj.Metadata.Name == "" {
+		t.Fatal("expected a generated name")
+	}
+	if pj.Status.State != kube.EnqueuedState {
+		t.Errorf("expected EnqueuedState, got %v", pj.Status.State)
+	}
+}
+
+func TestNewProwJobBindsPrebuilt(t *testing.T) {
+	labels := map[string]string{
+		PrebuiltProwJobLabel: "reserved-slot",
+		"extra":              "label",
+	}
+	spec := kube.ProwJobSpec{Job: "some-job"}
+	pj := NewProwJob(spec, labels, nil)
+	if pj.Metadata.Name != "reserved-slot" {
+		t.Errorf("expected prebuilt name %q, got %q", "reserved-slot", pj.Metadata.Name)
+	}
+	if pj.Status.State != kube.EnqueuedState {
+		t.Errorf("expected EnqueuedState, got %v", pj.Status.State)
+	}
+	if pj.Metadata.Labels["extra"] != "label" {
+		t.Error("expected labels passed to NewProwJob to propagate to the bound shell")
+	}
+	if pj.Metadata.Labels[PrebuiltProwJobLabel] != "reserved-slot" {
+		t.Error("expected the prebuilt label itself to propagate")
+	}
+}
+
+func TestNewProwJobBindsActualReservationViaGetter(t *testing.T) {
+	reserved := kube.ProwJob{
+		Metadata: kube.ObjectMeta{
+			Name:   "reserved-slot",
+			Labels: map[string]string{"reserved-by": "tide"},
+		},
+		Spec: kube.ProwJobSpec{Job: "some-job"},
+		Status: kube.ProwJobStatus{
+			State: kube.TriggeredState,
+		},
+	}
+	get := func(name string) (kube.ProwJob, bool) {
+		if name == reserved.Metadata.Name {
+			return reserved, true
+		}
+		return kube.ProwJob{}, false
+	}
+
+	labels := map[string]string{
+		PrebuiltProwJobLabel: "reserved-slot",
+		"extra":              "label",
+	}
+	triggerSpec := kube.ProwJobSpec{Job: "some-job", Refs: kube.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "abc"}}
+	pj := NewProwJob(triggerSpec, labels, get)
+	if pj.Metadata.Name != reserved.Metadata.Name {
+		t.Errorf("expected the reservation's own name %q, got %q", reserved.Metadata.Name, pj.Metadata.Name)
+	}
+	if pj.Status.State != kube.TriggeredState {
+		t.Errorf("expected the reservation's own TriggeredState to be retained, got %v", pj.Status.State)
+	}
+	if pj.Spec.Refs.BaseSHA != "abc" {
+		t.Errorf("expected the trigger-time spec (with its refs) to replace the reservation's, got %+v", pj.Spec)
+	}
+	if pj.Metadata.Labels["reserved-by"] != "tide" {
+		t.Error("expected the reservation's own labels to be retained")
+	}
+	if pj.Metadata.Labels["extra"] != "label" {
+		t.Error("expected labels passed at trigger time to merge into the retained reservation")
+	}
+
+	pj.Metadata.Labels["mutated"] = "true"
+	if _, ok := reserved.Metadata.Labels["mutated"]; ok {
+		t.Error("mutating the bound ProwJob's labels leaked into the getter's own reservation map")
+	}
+}
+
+func TestNewProwJobGetterMissFallsBackToPrebuiltProwJob(t *testing.T) {
+	get := func(name string) (kube.ProwJob, bool) { return kube.ProwJob{}, false }
+	labels := map[string]string{PrebuiltProwJobLabel: "reserved-slot"}
+	pj := NewProwJob(kube.ProwJobSpec{Job: "some-job"}, labels, get)
+	if pj.Metadata.Name != "reserved-slot" {
+		t.Errorf("expected fallback to the fabricated shell named %q, got %q", "reserved-slot", pj.Metadata.Name)
+	}
+	if pj.Status.State != kube.EnqueuedState {
+		t.Errorf("expected the fabricated shell's EnqueuedState, got %v", pj.Status.State)
+	}
+}
+
+func TestPrebuiltProwJobMissingLabel(t *testing.T) {
+	if _, ok := PrebuiltProwJob("", kube.ProwJobSpec{Job: "some-job"}); ok {
+		t.Error("expected no prebuilt shell for an empty label")
+	}
+}
+
+func TestPrebuiltKeyCollisionAndStability(t *testing.T) {
+	a := kube.ProwJobSpec{Job: "some-job", Type: kube.PresubmitJob, Refs: kube.Refs{Org: "o", Repo: "r", BaseRef: "master", BaseSHA: "abc"}}
+	b := a
+	if PrebuiltKey(a) != PrebuiltKey(b) {
+		t.Error("expected identical specs to collide onto the same key")
+	}
+	c := a
+	c.Job = "other-job"
+	if PrebuiltKey(a) == PrebuiltKey(c) {
+		t.Error("expected different jobs to produce different keys")
+	}
+}
+
+func TestProwJobToPodNoGangSchedule(t *testing.T) {
+	pj := kube.ProwJob{
+		Metadata: kube.ObjectMeta{Name: "some-job"},
+		Spec: kube.ProwJobSpec{
+			Type: kube.PeriodicJob,
+			Job:  "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+		},
+	}
+	pods, group, service, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 1 {
+		t.Fatalf("expected exactly one pod for a single-replica job, got %d", len(pods))
+	}
+	pod := pods[0]
+	if service != nil {
+		t.Errorf("expected no Service for a single-replica job, got %+v", service)
+	}
+	if group != nil {
+		t.Errorf("expected no PodGroup without GangSchedule, got %+v", group)
+	}
+	if pod.Spec.SchedulerName != "" {
+		t.Errorf("expected no scheduler name, got %q", pod.Spec.SchedulerName)
+	}
+	if _, ok := pod.Metadata.Annotations[kube.GangScheduleGroupNameAnnotation]; ok {
+		t.Error("expected no group-name annotation without GangSchedule")
+	}
+}
+
+func TestProwJobToPodGangSchedule(t *testing.T) {
+	pj := kube.ProwJob{
+		Metadata: kube.ObjectMeta{Name: "some-job", Labels: map[string]string{"foo": "bar"}},
+		Spec: kube.ProwJobSpec{
+			Type: kube.PeriodicJob,
+			Job:  "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+			GangSchedule: &kube.GangSchedule{
+				MinMember:    3,
+				MinResources: map[string]string{"cpu": "6"},
+				Queue:        "e2e",
+			},
+		},
+	}
+	pods, group, _, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := pods[0]
+	if pod.Spec.SchedulerName != kube.DefaultGangSchedulerName {
+		t.Errorf("expected default scheduler name %q, got %q", kube.DefaultGangSchedulerName, pod.Spec.SchedulerName)
+	}
+	if got := pod.Metadata.Annotations[kube.GangScheduleGroupNameAnnotation]; got != "some-job" {
+		t.Errorf("expected group-name annotation %q, got %q", "some-job", got)
+	}
+	if group == nil {
+		t.Fatal("expected a PodGroup")
+	}
+	if group.Metadata.Name != pj.Metadata.Name {
+		t.Errorf("expected PodGroup name %q, got %q", pj.Metadata.Name, group.Metadata.Name)
+	}
+	if group.Spec.MinMember != 3 {
+		t.Errorf("expected MinMember 3, got %d", group.Spec.MinMember)
+	}
+	if group.Spec.Queue != "e2e" {
+		t.Errorf("expected Queue %q, got %q", "e2e", group.Spec.Queue)
+	}
+	if got := group.Spec.MinResources["cpu"]; got != "6" {
+		t.Errorf("expected MinResources[cpu] %q, got %q", "6", got)
+	}
+
+	group.Metadata.Labels["foo"] = "mutated"
+	group.Spec.MinResources["cpu"] = "9001"
+	if got := pj.Metadata.Labels["foo"]; got != "bar" {
+		t.Errorf("mutating the PodGroup's labels leaked into the ProwJob's: got %q", got)
+	}
+	if got := pj.Spec.GangSchedule.MinResources["cpu"]; got != "6" {
+		t.Errorf("mutating the PodGroup's MinResources leaked into the ProwJob's: got %q", got)
+	}
+}
+
+func TestProwJobToPodGangScheduleCustomScheduler(t *testing.T) {
+	pj := kube.ProwJob{
+		Metadata: kube.ObjectMeta{Name: "some-job"},
+		Spec: kube.ProwJobSpec{
+			Type: kube.PeriodicJob,
+			Job:  "some-job",
+			PodSpec: kube.PodSpec{
+				Containers: []kube.Container{{}},
+			},
+			GangSchedule: &kube.GangSchedule{
+				MinMember:     2,
+				SchedulerName: "volcano",
+			},
+		},
+	}
+	pods, _, _, err := ProwJobToPod(pj, "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	pod := pods[0]
+	if pod.Spec.SchedulerName != "volcano" {
+		t.Errorf("expected scheduler name %q, got %q", "volcano", pod.Spec.SchedulerName)
+	}
+}
+
+func TestPartitionPending(t *testing.T) {
+	pjs := []kube.ProwJob{
+		{Status: kube.ProwJobStatus{State: kube.PendingState}},
+		{Status: kube.ProwJobStatus{State: kube.EnqueuedState}},
+		{Status: kube.ProwJobStatus{State: kube.EnqueuedState}},
+		{Status: kube.ProwJobStatus{State: kube.TriggeredState}},
+		{Status: kube.ProwJobStatus{State: kube.SuccessState}},
+	}
+	pending, enqueued, nonPending := PartitionPending(pjs)
+	var pendingCount, enqueuedCount, nonPendingCount int
+	for range pending {
+		pendingCount++
+	}
+	for range enqueued {
+		enqueuedCount++
+	}
+	for range nonPending {
+		nonPendingCount++
+	}
+	if pendingCount != 1 {
+		t.Errorf("expected 1 pending job, got %d", pendingCount)
+	}
+	if enqueuedCount != 2 {
+		t.Errorf("expected 2 enqueued jobs, got %d", enqueuedCount)
+	}
+	if nonPendingCount != 2 {
+		t.Errorf("expected 2 remaining jobs, got %d", nonPendingCount)
+	}
+}